@@ -0,0 +1,98 @@
+package main
+
+import "testing"
+
+// TestArcEvictsLRUAtCapacity exercises the |T1|+|B1| == c boundary in its
+// simplest form: with no ghost entries yet, a store at capacity evicts its
+// LRU key outright rather than ghosting it.
+func TestArcEvictsLRUAtCapacity(t *testing.T) {
+	db := NewMemStore(2)
+
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+	mustPut(t, db, "c", "3")
+	mustPut(t, db, "d", "4")
+
+	if _, found, _ := db.Get("a"); found {
+		t.Fatalf("a: expected evicted, still found")
+	}
+	if _, found, _ := db.Get("b"); found {
+		t.Fatalf("b: expected evicted, still found")
+	}
+	mustHit(t, db, "c", "3")
+	mustHit(t, db, "d", "4")
+
+	if stats := db.Stats(); stats.Evictions != 2 {
+		t.Fatalf("Evictions = %d, want 2", stats.Evictions)
+	}
+}
+
+// TestArcGhostHitInB1AdaptsP drives a key through T1 -> evicted into B1 ->
+// re-put while still a ghost, and checks that the ghost hit grows p and
+// resurrects the key as a live, promoted entry rather than a fresh insert.
+func TestArcGhostHitInB1AdaptsP(t *testing.T) {
+	db := NewMemStore(2)
+
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+	mustHit(t, db, "a", "1") // promotes a into T2, leaving b as T1's LRU
+	mustPut(t, db, "c", "3") // evicts b into B1
+
+	mustPut(t, db, "b", "2b") // ghost hit in B1
+
+	if db.p <= 0 {
+		t.Fatalf("p = %d after a B1 ghost hit, want > 0", db.p)
+	}
+	mustHit(t, db, "b", "2b")
+	mustHit(t, db, "c", "3")
+	if _, found, _ := db.Get("a"); found {
+		t.Fatalf("a: expected evicted into B2 by the ghost hit's replace, still found")
+	}
+	if stats := db.Stats(); stats.Evictions != 2 {
+		t.Fatalf("Evictions = %d, want 2", stats.Evictions)
+	}
+}
+
+// TestArcGhostHitInB2AdaptsP mirrors TestArcGhostHitInB1AdaptsP for the
+// other ghost list: a key promoted into T2, evicted into B2, then re-put
+// while ghosted should come back as a live entry and leave p clamped at
+// its floor of zero rather than going negative.
+func TestArcGhostHitInB2AdaptsP(t *testing.T) {
+	db := NewMemStore(2)
+
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+	mustHit(t, db, "a", "1") // promotes a into T2
+	mustPut(t, db, "x", "1x") // evicts b into B1
+	mustPut(t, db, "x", "1x") // re-put while resident in T1: promotes x into T2
+	mustPut(t, db, "y", "1y") // evicts a (T2's LRU) into B2
+
+	mustPut(t, db, "a", "2a") // ghost hit in B2
+
+	if db.p != 0 {
+		t.Fatalf("p = %d after a B2 ghost hit from p=0, want 0 (clamped)", db.p)
+	}
+	mustHit(t, db, "a", "2a")
+	mustHit(t, db, "x", "1x")
+	if _, found, _ := db.Get("y"); found {
+		t.Fatalf("y: expected evicted into B1 by the ghost hit's replace, still found")
+	}
+	if stats := db.Stats(); stats.Evictions != 3 {
+		t.Fatalf("Evictions = %d, want 3", stats.Evictions)
+	}
+}
+
+func mustPut(t *testing.T, db *MemStore, key, value string) {
+	t.Helper()
+	if err := db.Put(key, value); err != nil {
+		t.Fatalf("Put(%q, %q): %s", key, value, err)
+	}
+}
+
+func mustHit(t *testing.T, db *MemStore, key, want string) {
+	t.Helper()
+	val, found, err := db.Get(key)
+	if err != nil || !found || val != want {
+		t.Fatalf("Get(%q) = %q, %v, %v; want %q, true, nil", key, val, found, err, want)
+	}
+}
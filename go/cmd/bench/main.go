@@ -0,0 +1,138 @@
+// Command bench is a load generator for simple-kv: it opens N concurrent
+// connections to a running server, issues a mix of GET/PUT operations
+// against a fixed-size keyspace, and reports throughput and a latency
+// distribution, for regression-testing the server's connection handling
+// under load.
+package main
+
+import "bufio"
+import "flag"
+import "fmt"
+import "log"
+import "math/rand"
+import "net"
+import "sync"
+import "sync/atomic"
+import "time"
+
+import hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+
+// benchValue is the fixed payload written by every PUT; its size is what
+// matters for the benchmark, not its content.
+const benchValue = "0123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890123456789"
+
+func main() {
+	addr := flag.String("addr", "localhost:5556", "address of a running simple-kv server")
+	workers := flag.Int("workers", 50, "number of concurrent connections")
+	keyspace := flag.Int("keyspace", 10000, "number of distinct keys to read and write")
+	duration := flag.Duration("duration", 30*time.Second, "how long to run the benchmark, if -requests is 0")
+	requests := flag.Int64("requests", 0, "total requests to issue across all workers (0 = run for -duration instead)")
+	readRatio := flag.Float64("read-ratio", 0.9, "fraction of operations that are GET rather than PUT")
+	flag.Parse()
+
+	var completed, failed int64
+	start := time.Now()
+	deadline := start.Add(*duration)
+
+	var wg sync.WaitGroup
+	histograms := make([]*hdrhistogram.Histogram, *workers)
+	for i := 0; i < *workers; i++ {
+		histograms[i] = hdrhistogram.New(1, int64(time.Minute/time.Microsecond), 3)
+		wg.Add(1)
+		go func(hist *hdrhistogram.Histogram) {
+			defer wg.Done()
+			runWorker(*addr, *keyspace, *readRatio, *requests, deadline, hist, &completed, &failed)
+		}(histograms[i])
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	reportProgress(done, &completed, &failed)
+
+	merged := hdrhistogram.New(1, int64(time.Minute/time.Microsecond), 3)
+	for _, hist := range histograms {
+		merged.Merge(hist)
+	}
+	reportSummary(time.Since(start), atomic.LoadInt64(&completed), atomic.LoadInt64(&failed), merged)
+}
+
+// runWorker holds one connection open and issues requests against it
+// until the shared request budget (if any) or the deadline is reached.
+func runWorker(addr string, keyspace int, readRatio float64, requestBudget int64, deadline time.Time, hist *hdrhistogram.Histogram, completed, failed *int64) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		log.Printf("worker: unable to connect to %s: %s", addr, err)
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for {
+		if requestBudget > 0 {
+			if atomic.LoadInt64(completed) >= requestBudget {
+				return
+			}
+		} else if time.Now().After(deadline) {
+			return
+		}
+
+		key := fmt.Sprintf("bench:%d", rnd.Intn(keyspace))
+		start := time.Now()
+		var err error
+		if rnd.Float64() < readRatio {
+			_, err = fmt.Fprintf(conn, "GET %s\n", key)
+		} else {
+			_, err = fmt.Fprintf(conn, "PUT %s %s\n", key, benchValue)
+		}
+		if err == nil {
+			_, err = r.ReadString('\n')
+		}
+		elapsed := time.Since(start)
+
+		if err != nil {
+			atomic.AddInt64(failed, 1)
+			return
+		}
+		atomic.AddInt64(completed, 1)
+		hist.RecordValue(elapsed.Microseconds())
+
+		if requestBudget > 0 && atomic.LoadInt64(completed) >= requestBudget {
+			return
+		}
+	}
+}
+
+// reportProgress logs the combined throughput once a second until done
+// is closed, matching the load-generator pattern used to watch the
+// server's connection handling hold up under sustained concurrency.
+func reportProgress(done <-chan struct{}, completed, failed *int64) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var last int64
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			now := atomic.LoadInt64(completed)
+			log.Printf("%d req/s (%d completed, %d failed)", now-last, now, atomic.LoadInt64(failed))
+			last = now
+		}
+	}
+}
+
+func reportSummary(elapsed time.Duration, completed, failed int64, hist *hdrhistogram.Histogram) {
+	fmt.Printf("\n%d requests in %s (%d failed)\n", completed, elapsed.Round(time.Millisecond), failed)
+	fmt.Printf("%.1f req/s\n", float64(completed)/elapsed.Seconds())
+	fmt.Printf("latency (us): p50=%d p95=%d p99=%d max=%d\n",
+		hist.ValueAtQuantile(50),
+		hist.ValueAtQuantile(95),
+		hist.ValueAtQuantile(99),
+		hist.Max(),
+	)
+}
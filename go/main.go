@@ -1,52 +1,1065 @@
 package main
 
 import "bufio"
+import "crypto/tls"
+import "crypto/x509"
+import "encoding/binary"
+import "errors"
+import "flag"
+import "io"
 import "log"
 import "net"
+import "os"
+import "container/list"
+import "strconv"
 import "strings"
 import "fmt"
 import "sync"
+import "sync/atomic"
+import "time"
 
-type DB struct {
-	mu      sync.Mutex
-	entries map[string]string
+import "os/exec"
+
+import "github.com/syndtr/goleveldb/leveldb"
+import goplugin "github.com/hashicorp/go-plugin"
+
+import "github.com/danburkert/simple-kv/plugin"
+
+// Store is the storage backend interface that the wire protocol handlers
+// are written against. It is implemented by the in-memory map (MemStore),
+// a LevelDB-backed store (LevelDBStore), and an append-only-log store
+// (AOFStore), selected at startup by the -backend flag.
+//
+// TTL is expressed wherever possible the way this command returns it to a
+// caller: Expire and PutEX take a duration from now; TTL returns the
+// remaining duration, with -1 meaning the key exists but has no expiry.
+type Store interface {
+	Get(key string) (string, bool, error)
+	Put(key, value string) error
+	PutEX(key, value string, ttl time.Duration) error
+	Delete(key string) (bool, error)
+	Expire(key string, ttl time.Duration) (bool, error)
+	TTL(key string) (time.Duration, bool, error)
+	Persist(key string) (bool, error)
+	Scan(fn func(key, value string) bool) error
+	Stats() StoreStats
+}
+
+// StoreStats are the counters exposed by the STATS command. Backends that
+// don't track a particular counter (e.g. stores with no cache eviction)
+// report zero for it.
+type StoreStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// noExpiry means TTL reports a key as persistent.
+const noExpiry time.Duration = -1
+
+// memEntry holds a value and its optional expiration time. A zero
+// expiresAt means the key never expires.
+type memEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (e memEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// setEntry stores entry under key and keeps withTTL in sync, so every
+// write path feeds the sweeper's TTL index instead of only the sweeper
+// caring about expiresAt. Callers must hold db.mu.
+func (db *MemStore) setEntry(key string, entry memEntry) {
+	db.entries[key] = entry
+	if entry.expiresAt.IsZero() {
+		delete(db.withTTL, key)
+	} else {
+		db.withTTL[key] = struct{}{}
+	}
+}
+
+// deleteEntry removes key from entries and withTTL. Callers must hold db.mu.
+func (db *MemStore) deleteEntry(key string) {
+	delete(db.entries, key)
+	delete(db.withTTL, key)
+}
+
+// Active expiration sweep parameters, modeled on Redis: wake up
+// periodically, sample a bounded number of keys that carry a TTL, and
+// keep sweeping without sleeping as long as more than sweepExpiredRatio
+// of the sample was already expired.
+const (
+	sweepInterval     = 100 * time.Millisecond
+	sweepSampleSize   = 20
+	sweepExpiredRatio = 0.25
+)
+
+// MemStore is the original map-backed store, extended with per-key TTLs,
+// a background sweeper that actively reclaims expired entries, and,
+// when maxEntries is non-zero, ARC (Adaptive Replacement Cache) eviction
+// so the store never holds more than maxEntries keys.
+type MemStore struct {
+	mu      sync.RWMutex
+	entries map[string]memEntry
+
+	// ARC bookkeeping. Unused (maxEntries == 0) means unbounded growth,
+	// matching the original store's behavior.
+	maxEntries int
+	p          int // adaptive target length for t1
+	t1, t2     *list.List
+	b1, b2     *list.List // ghost lists: keys only, no values
+	t1idx      map[string]*list.Element
+	t2idx      map[string]*list.Element
+	b1idx      map[string]*list.Element
+	b2idx      map[string]*list.Element
+
+	// withTTL indexes the subset of entries that carry an expiration, so
+	// the sweeper can sample TTL-bearing keys without scanning the full
+	// (possibly much larger) entries map.
+	withTTL map[string]struct{}
+
+	stats StoreStats
+}
+
+func NewMemStore(maxEntries int) *MemStore {
+	db := &MemStore{
+		entries:    make(map[string]memEntry),
+		withTTL:    make(map[string]struct{}),
+		maxEntries: maxEntries,
+	}
+	if maxEntries > 0 {
+		db.t1, db.t2, db.b1, db.b2 = list.New(), list.New(), list.New(), list.New()
+		db.t1idx = make(map[string]*list.Element)
+		db.t2idx = make(map[string]*list.Element)
+		db.b1idx = make(map[string]*list.Element)
+		db.b2idx = make(map[string]*list.Element)
+	}
+	go db.sweepLoop()
+	return db
+}
+
+func (db *MemStore) Get(key string) (string, bool, error) {
+	if db.maxEntries <= 0 {
+		db.mu.RLock()
+		e, ok := db.entries[key]
+		db.mu.RUnlock()
+		if !ok {
+			atomic.AddUint64(&db.stats.Misses, 1)
+			return "", false, nil
+		}
+		if e.expired(time.Now()) {
+			db.evictIfExpired(key)
+			atomic.AddUint64(&db.stats.Misses, 1)
+			return "", false, nil
+		}
+		atomic.AddUint64(&db.stats.Hits, 1)
+		return e.value, true, nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	e, ok := db.entries[key]
+	if !ok {
+		atomic.AddUint64(&db.stats.Misses, 1)
+		return "", false, nil
+	}
+	if e.expired(time.Now()) {
+		db.deleteEntry(key)
+		listRemove(db.t1, db.t1idx, key)
+		listRemove(db.t2, db.t2idx, key)
+		atomic.AddUint64(&db.stats.Misses, 1)
+		return "", false, nil
+	}
+	db.arcPromote(key)
+	atomic.AddUint64(&db.stats.Hits, 1)
+	return e.value, true, nil
+}
+
+func (db *MemStore) Put(key, value string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.arcPut(key, memEntry{value: value})
+	return nil
+}
+
+func (db *MemStore) PutEX(key, value string, ttl time.Duration) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.arcPut(key, memEntry{value: value, expiresAt: time.Now().Add(ttl)})
+	return nil
+}
+
+func (db *MemStore) Delete(key string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	_, ok := db.entries[key]
+	db.deleteEntry(key)
+	if db.maxEntries > 0 {
+		listRemove(db.t1, db.t1idx, key)
+		listRemove(db.t2, db.t2idx, key)
+	}
+	return ok, nil
+}
+
+func (db *MemStore) Stats() StoreStats {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.stats
+}
+
+func (db *MemStore) Expire(key string, ttl time.Duration) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	e, ok := db.entries[key]
+	if !ok || e.expired(time.Now()) {
+		db.deleteEntry(key)
+		return false, nil
+	}
+	e.expiresAt = time.Now().Add(ttl)
+	db.setEntry(key, e)
+	return true, nil
 }
 
-func (db *DB) Get(key string) (string, bool) {
+func (db *MemStore) TTL(key string) (time.Duration, bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	e, ok := db.entries[key]
+	if !ok || e.expired(time.Now()) {
+		return 0, false, nil
+	}
+	if e.expiresAt.IsZero() {
+		return noExpiry, true, nil
+	}
+	return e.expiresAt.Sub(time.Now()), true, nil
+}
+
+func (db *MemStore) Persist(key string) (bool, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	val, ok := db.entries[key]
-	return val, ok
+	e, ok := db.entries[key]
+	if !ok || e.expired(time.Now()) || e.expiresAt.IsZero() {
+		return false, nil
+	}
+	e.expiresAt = time.Time{}
+	db.setEntry(key, e)
+	return true, nil
 }
 
-func (db *DB) Put(key, value string) {
+func (db *MemStore) Scan(fn func(key, value string) bool) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	now := time.Now()
+	for k, e := range db.entries {
+		if e.expired(now) {
+			continue
+		}
+		if !fn(k, e.value) {
+			break
+		}
+	}
+	return nil
+}
+
+// rawEntry exposes the stored entry, including its expiration, to the AOF
+// backend so that it can log a full snapshot of a key after a mutation.
+func (db *MemStore) rawEntry(key string) (memEntry, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	e, ok := db.entries[key]
+	if ok && e.expired(time.Now()) {
+		return memEntry{}, false
+	}
+	return e, ok
+}
+
+func (db *MemStore) evictIfExpired(key string) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	db.entries[key] = value
+	if e, ok := db.entries[key]; ok && e.expired(time.Now()) {
+		db.deleteEntry(key)
+	}
+}
+
+// sweepLoop wakes up every sweepInterval and sweeps until the sampled
+// expired ratio drops at or below sweepExpiredRatio, so a burst of
+// simultaneously expiring keys is reclaimed promptly instead of trickling
+// out one tick at a time.
+func (db *MemStore) sweepLoop() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for db.sweepOnce() > sweepExpiredRatio {
+		}
+	}
+}
+
+// sweepOnce samples up to sweepSampleSize keys from the TTL index, deletes
+// whichever of them are past their deadline under a single write lock, and
+// returns the fraction of the sample that was expired. Sampling from
+// withTTL rather than entries keeps the cost proportional to the number of
+// keys that carry a TTL, not the size of the whole keyspace.
+func (db *MemStore) sweepOnce() float64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	sampled := 0
+	var toDelete []string
+	for key := range db.withTTL {
+		e := db.entries[key]
+		sampled++
+		if now.After(e.expiresAt) {
+			toDelete = append(toDelete, key)
+		}
+		if sampled >= sweepSampleSize {
+			break
+		}
+	}
+	for _, key := range toDelete {
+		db.deleteEntry(key)
+		if db.maxEntries > 0 {
+			listRemove(db.t1, db.t1idx, key)
+			listRemove(db.t2, db.t2idx, key)
+		}
+	}
+	if sampled == 0 {
+		return 0
+	}
+	return float64(len(toDelete)) / float64(sampled)
+}
+
+// listRemove removes key from l, if present, keeping idx in sync.
+func listRemove(l *list.List, idx map[string]*list.Element, key string) {
+	if e, ok := idx[key]; ok {
+		l.Remove(e)
+		delete(idx, key)
+	}
+}
+
+// listPushFront inserts key at the MRU end of l.
+func listPushFront(l *list.List, idx map[string]*list.Element, key string) {
+	idx[key] = l.PushFront(key)
+}
+
+// listBackKey returns the key at the LRU end of l, if any.
+func listBackKey(l *list.List) (string, bool) {
+	e := l.Back()
+	if e == nil {
+		return "", false
+	}
+	return e.Value.(string), true
+}
+
+// arcPromote moves a key already resident in T1 or T2 to the MRU end of
+// T2: ARC's case I, a hit on a cached key.
+func (db *MemStore) arcPromote(key string) {
+	if _, ok := db.t1idx[key]; ok {
+		listRemove(db.t1, db.t1idx, key)
+	} else {
+		listRemove(db.t2, db.t2idx, key)
+	}
+	listPushFront(db.t2, db.t2idx, key)
+}
+
+// arcPut stores entry under key, running the ARC(c) algorithm when
+// maxEntries is set: promote on a cache hit, adapt p and replace on a
+// ghost hit in B1/B2, or evict to make room for a fresh key. c is the
+// combined size limit for T1+T2.
+func (db *MemStore) arcPut(key string, entry memEntry) {
+	if db.maxEntries <= 0 {
+		db.setEntry(key, entry)
+		return
+	}
+	c := db.maxEntries
+
+	if _, ok := db.t1idx[key]; ok {
+		listRemove(db.t1, db.t1idx, key)
+		listPushFront(db.t2, db.t2idx, key)
+		db.setEntry(key, entry)
+		return
+	}
+	if _, ok := db.t2idx[key]; ok {
+		listRemove(db.t2, db.t2idx, key)
+		listPushFront(db.t2, db.t2idx, key)
+		db.setEntry(key, entry)
+		return
+	}
+	if _, ok := db.b1idx[key]; ok {
+		b1n, b2n := db.b1.Len(), db.b2.Len()
+		delta := 1
+		if b1n > 0 {
+			if d := b2n / b1n; d > delta {
+				delta = d
+			}
+		}
+		db.p += delta
+		if db.p > c {
+			db.p = c
+		}
+		db.arcReplace(false)
+		listRemove(db.b1, db.b1idx, key)
+		listPushFront(db.t2, db.t2idx, key)
+		db.setEntry(key, entry)
+		return
+	}
+	if _, ok := db.b2idx[key]; ok {
+		b1n, b2n := db.b1.Len(), db.b2.Len()
+		delta := 1
+		if b2n > 0 {
+			if d := b1n / b2n; d > delta {
+				delta = d
+			}
+		}
+		db.p -= delta
+		if db.p < 0 {
+			db.p = 0
+		}
+		db.arcReplace(true)
+		listRemove(db.b2, db.b2idx, key)
+		listPushFront(db.t2, db.t2idx, key)
+		db.setEntry(key, entry)
+		return
+	}
+
+	// Fresh key: not resident and not ghosted.
+	t1n, b1n := db.t1.Len(), db.b1.Len()
+	if t1n+b1n == c {
+		if t1n < c {
+			if k, ok := listBackKey(db.b1); ok {
+				listRemove(db.b1, db.b1idx, k)
+			}
+			db.arcReplace(false)
+		} else if k, ok := listBackKey(db.t1); ok {
+			listRemove(db.t1, db.t1idx, k)
+			db.deleteEntry(k)
+			db.stats.Evictions++
+		}
+	} else if total := db.t1.Len() + db.t2.Len() + db.b1.Len() + db.b2.Len(); total >= c {
+		if total >= 2*c {
+			if k, ok := listBackKey(db.b2); ok {
+				listRemove(db.b2, db.b2idx, k)
+			}
+		}
+		db.arcReplace(false)
+	}
+	listPushFront(db.t1, db.t1idx, key)
+	db.setEntry(key, entry)
+}
+
+// arcReplace evicts the LRU entry of T1 or T2 into the matching ghost
+// list, per ARC's REPLACE(x): T1 is preferred once it has grown past the
+// adaptive target p (or a ghost hit in B2 pushed it there).
+func (db *MemStore) arcReplace(ghostHitInB2 bool) {
+	t1n := db.t1.Len()
+	if t1n > 0 && (t1n > db.p || (ghostHitInB2 && t1n == db.p)) {
+		if k, ok := listBackKey(db.t1); ok {
+			listRemove(db.t1, db.t1idx, k)
+			listPushFront(db.b1, db.b1idx, k)
+			db.deleteEntry(k)
+			db.stats.Evictions++
+		}
+		return
+	}
+	if k, ok := listBackKey(db.t2); ok {
+		listRemove(db.t2, db.t2idx, k)
+		listPushFront(db.b2, db.b2idx, k)
+		db.deleteEntry(k)
+		db.stats.Evictions++
+	}
+}
+
+// LevelDBStore persists entries in a LevelDB database directory.
+type LevelDBStore struct {
+	db *leveldb.DB
+}
+
+func NewLevelDBStore(path string) (*LevelDBStore, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open leveldb at %s: %s", path, err)
+	}
+	return &LevelDBStore{db: db}, nil
+}
+
+// encodeLevelDBValue prepends the expiration, as Unix nanoseconds (0 for
+// no expiry), to the value so LevelDB entries carry their own TTL.
+func encodeLevelDBValue(value string, expiresAt time.Time) []byte {
+	buf := make([]byte, 8+len(value))
+	var nanos int64
+	if !expiresAt.IsZero() {
+		nanos = expiresAt.UnixNano()
+	}
+	binary.BigEndian.PutUint64(buf, uint64(nanos))
+	copy(buf[8:], value)
+	return buf
+}
+
+func decodeLevelDBValue(raw []byte) (value string, expiresAt time.Time) {
+	nanos := int64(binary.BigEndian.Uint64(raw[:8]))
+	if nanos != 0 {
+		expiresAt = time.Unix(0, nanos)
+	}
+	return string(raw[8:]), expiresAt
+}
+
+func (s *LevelDBStore) get(key string) (value string, expiresAt time.Time, ok bool, err error) {
+	raw, err := s.db.Get([]byte(key), nil)
+	if err == leveldb.ErrNotFound {
+		return "", time.Time{}, false, nil
+	}
+	if err != nil {
+		return "", time.Time{}, false, err
+	}
+	value, expiresAt = decodeLevelDBValue(raw)
+	if !expiresAt.IsZero() && time.Now().After(expiresAt) {
+		s.db.Delete([]byte(key), nil)
+		return "", time.Time{}, false, nil
+	}
+	return value, expiresAt, true, nil
+}
+
+func (s *LevelDBStore) Get(key string) (string, bool, error) {
+	value, _, ok, err := s.get(key)
+	return value, ok, err
+}
+
+func (s *LevelDBStore) Put(key, value string) error {
+	return s.db.Put([]byte(key), encodeLevelDBValue(value, time.Time{}), nil)
+}
+
+func (s *LevelDBStore) PutEX(key, value string, ttl time.Duration) error {
+	return s.db.Put([]byte(key), encodeLevelDBValue(value, time.Now().Add(ttl)), nil)
+}
+
+func (s *LevelDBStore) Delete(key string) (bool, error) {
+	_, ok, err := s.Get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, s.db.Delete([]byte(key), nil)
+}
+
+func (s *LevelDBStore) Expire(key string, ttl time.Duration) (bool, error) {
+	value, _, ok, err := s.get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, s.db.Put([]byte(key), encodeLevelDBValue(value, time.Now().Add(ttl)), nil)
+}
+
+func (s *LevelDBStore) TTL(key string) (time.Duration, bool, error) {
+	_, expiresAt, ok, err := s.get(key)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+	if expiresAt.IsZero() {
+		return noExpiry, true, nil
+	}
+	return expiresAt.Sub(time.Now()), true, nil
+}
+
+func (s *LevelDBStore) Persist(key string) (bool, error) {
+	value, expiresAt, ok, err := s.get(key)
+	if err != nil || !ok || expiresAt.IsZero() {
+		return false, err
+	}
+	return true, s.db.Put([]byte(key), encodeLevelDBValue(value, time.Time{}), nil)
+}
+
+func (s *LevelDBStore) Scan(fn func(key, value string) bool) error {
+	iter := s.db.NewIterator(nil, nil)
+	defer iter.Release()
+	now := time.Now()
+	for iter.Next() {
+		value, expiresAt := decodeLevelDBValue(iter.Value())
+		if !expiresAt.IsZero() && now.After(expiresAt) {
+			continue
+		}
+		if !fn(string(iter.Key()), value) {
+			break
+		}
+	}
+	return iter.Error()
+}
+
+// Stats always reports zero counters: LevelDB has no eviction policy for
+// the STATS command to surface.
+func (s *LevelDBStore) Stats() StoreStats {
+	return StoreStats{}
+}
+
+// fsyncPolicy controls how often AOFStore flushes its log to disk.
+type fsyncPolicy int
+
+const (
+	fsyncAlways fsyncPolicy = iota
+	fsyncEverysec
+	fsyncNo
+)
+
+func parseFsyncPolicy(s string) (fsyncPolicy, error) {
+	switch s {
+	case "always":
+		return fsyncAlways, nil
+	case "everysec":
+		return fsyncEverysec, nil
+	case "no":
+		return fsyncNo, nil
+	default:
+		return 0, fmt.Errorf("unknown fsync policy %q", s)
+	}
+}
+
+const (
+	// aofOpSet records a key's full post-mutation state (value and
+	// expiration), so Put, PutEX, Expire, and Persist all replay the same
+	// way: set the key to exactly this snapshot.
+	aofOpSet byte = iota
+	aofOpDelete
+)
+
+// AOFStore keeps the working set in memory and appends every mutation to
+// a length-prefixed record in a log file, replaying the log to rebuild
+// the map on startup.
+type AOFStore struct {
+	mem *MemStore
+	// opMu serializes each mutating Store method end-to-end, from the
+	// mem update through the matching AOF append, so concurrent writers
+	// to different keys can never interleave the log out of operation
+	// order.
+	opMu   sync.Mutex
+	mu     sync.Mutex
+	log    *os.File
+	policy fsyncPolicy
+}
+
+func NewAOFStore(path string, policy fsyncPolicy, maxEntries int) (*AOFStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open AOF log at %s: %s", path, err)
+	}
+	mem := NewMemStore(maxEntries)
+	if err := replayAOF(f, mem); err != nil {
+		return nil, fmt.Errorf("unable to replay AOF log at %s: %s", path, err)
+	}
+	s := &AOFStore{mem: mem, log: f, policy: policy}
+	if policy == fsyncEverysec {
+		go s.sync1sec()
+	}
+	return s, nil
+}
+
+func (s *AOFStore) sync1sec() {
+	for {
+		time.Sleep(time.Second)
+		s.mu.Lock()
+		s.log.Sync()
+		s.mu.Unlock()
+	}
+}
+
+func replayAOF(f *os.File, mem *MemStore) error {
+	r := bufio.NewReader(f)
+	for {
+		op, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		key, err := readAOFField(r)
+		if err != nil {
+			return err
+		}
+		switch op {
+		case aofOpSet:
+			var nanos int64
+			if err := binary.Read(r, binary.BigEndian, &nanos); err != nil {
+				return err
+			}
+			value, err := readAOFField(r)
+			if err != nil {
+				return err
+			}
+			if nanos == 0 {
+				mem.Put(key, value)
+			} else {
+				mem.PutEX(key, value, time.Until(time.Unix(0, nanos)))
+			}
+		case aofOpDelete:
+			mem.Delete(key)
+		default:
+			return fmt.Errorf("corrupt AOF log: unknown op %d", op)
+		}
+	}
+}
+
+func readAOFField(r *bufio.Reader) (string, error) {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func writeAOFField(w io.Writer, s string) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(s))); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte(s))
+	return err
+}
+
+func (s *AOFStore) appendSetRecord(key, value string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.log.Write([]byte{aofOpSet}); err != nil {
+		return err
+	}
+	if err := writeAOFField(s.log, key); err != nil {
+		return err
+	}
+	var nanos int64
+	if !expiresAt.IsZero() {
+		nanos = expiresAt.UnixNano()
+	}
+	if err := binary.Write(s.log, binary.BigEndian, nanos); err != nil {
+		return err
+	}
+	if err := writeAOFField(s.log, value); err != nil {
+		return err
+	}
+	if s.policy == fsyncAlways {
+		return s.log.Sync()
+	}
+	return nil
+}
+
+func (s *AOFStore) appendDeleteRecord(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.log.Write([]byte{aofOpDelete}); err != nil {
+		return err
+	}
+	if err := writeAOFField(s.log, key); err != nil {
+		return err
+	}
+	if s.policy == fsyncAlways {
+		return s.log.Sync()
+	}
+	return nil
+}
+
+// logCurrentState appends an AOF record reflecting the key's current
+// in-memory state, so Put, PutEX, Expire, and Persist all share the same
+// replay path.
+func (s *AOFStore) logCurrentState(key string) error {
+	e, ok := s.mem.rawEntry(key)
+	if !ok {
+		return s.appendDeleteRecord(key)
+	}
+	return s.appendSetRecord(key, e.value, e.expiresAt)
+}
+
+func (s *AOFStore) Get(key string) (string, bool, error) {
+	return s.mem.Get(key)
+}
+
+func (s *AOFStore) Put(key, value string) error {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	if err := s.mem.Put(key, value); err != nil {
+		return err
+	}
+	return s.logCurrentState(key)
+}
+
+func (s *AOFStore) PutEX(key, value string, ttl time.Duration) error {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	if err := s.mem.PutEX(key, value, ttl); err != nil {
+		return err
+	}
+	return s.logCurrentState(key)
+}
+
+func (s *AOFStore) Delete(key string) (bool, error) {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	ok, _ := s.mem.Delete(key)
+	if !ok {
+		return false, nil
+	}
+	return true, s.appendDeleteRecord(key)
+}
+
+func (s *AOFStore) Expire(key string, ttl time.Duration) (bool, error) {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	ok, err := s.mem.Expire(key, ttl)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return true, s.logCurrentState(key)
+}
+
+func (s *AOFStore) TTL(key string) (time.Duration, bool, error) {
+	return s.mem.TTL(key)
+}
+
+func (s *AOFStore) Persist(key string) (bool, error) {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	ok, err := s.mem.Persist(key)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return true, s.logCurrentState(key)
+}
+
+func (s *AOFStore) Stats() StoreStats {
+	return s.mem.Stats()
+}
+
+func (s *AOFStore) Scan(fn func(key, value string) bool) error {
+	return s.mem.Scan(fn)
+}
+
+// PluginStore forwards Get, Put and Delete to a KV storage plugin running
+// as a subprocess, connected to over the go-plugin gRPC boundary. Scan and
+// the TTL methods (PutEX, Expire, TTL, Persist) are not part of the plugin
+// protocol and always return an error.
+type PluginStore struct {
+	client *goplugin.Client
+	kv     plugin.KVStore
+}
+
+func NewPluginStore(path string) (*PluginStore, error) {
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig:  plugin.Handshake,
+		Plugins:          plugin.PluginMap,
+		Cmd:              exec.Command(path),
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+	})
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("unable to start storage plugin %s: %s", path, err)
+	}
+	raw, err := rpcClient.Dispense("kv")
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("unable to dispense kv plugin from %s: %s", path, err)
+	}
+	kv, ok := raw.(plugin.KVStore)
+	if !ok {
+		client.Kill()
+		return nil, fmt.Errorf("plugin %s does not implement KVStore", path)
+	}
+	return &PluginStore{client: client, kv: kv}, nil
+}
+
+func (s *PluginStore) Get(key string) (string, bool, error) {
+	return s.kv.Get(key)
+}
+
+func (s *PluginStore) Put(key, value string) error {
+	return s.kv.Put(key, value)
+}
+
+func (s *PluginStore) Delete(key string) (bool, error) {
+	return s.kv.Delete(key)
+}
+
+func (s *PluginStore) PutEX(key, value string, ttl time.Duration) error {
+	return errors.New("ttl is not supported by the plugin backend")
+}
+
+func (s *PluginStore) Expire(key string, ttl time.Duration) (bool, error) {
+	return false, errors.New("ttl is not supported by the plugin backend")
+}
+
+func (s *PluginStore) TTL(key string) (time.Duration, bool, error) {
+	return 0, false, errors.New("ttl is not supported by the plugin backend")
+}
+
+func (s *PluginStore) Persist(key string) (bool, error) {
+	return false, errors.New("ttl is not supported by the plugin backend")
+}
+
+func (s *PluginStore) Scan(fn func(key, value string) bool) error {
+	return errors.New("scan is not supported by the plugin backend")
+}
+
+// Stats always reports zero counters: the plugin protocol has no STATS
+// rpc for the host to forward to.
+func (s *PluginStore) Stats() StoreStats {
+	return StoreStats{}
+}
+
+func openStore(backend, dbPath, aofPath, pluginPath string, policy fsyncPolicy, maxEntries int) (Store, error) {
+	switch backend {
+	case "memory":
+		return NewMemStore(maxEntries), nil
+	case "leveldb":
+		return NewLevelDBStore(dbPath)
+	case "aof":
+		return NewAOFStore(aofPath, policy, maxEntries)
+	case "plugin":
+		return NewPluginStore(pluginPath)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", backend)
+	}
 }
 
 func main() {
-	log.Printf("Starting simple-kv Go server with listening port 5556")
-	ln, err := net.Listen("tcp", ":5556")
+	backend := flag.String("backend", "memory", "storage backend: memory, leveldb, aof, or plugin")
+	dbPath := flag.String("db-path", "simple-kv.db", "directory for the leveldb backend")
+	aofPath := flag.String("aof-path", "simple-kv.aof", "log file for the aof backend")
+	aofFsync := flag.String("aof-fsync", "everysec", "aof fsync policy: always, everysec, or no")
+	pluginPath := flag.String("plugin-path", "", "path to a KV storage plugin binary, for -backend=plugin")
+	maxEntries := flag.Int("max-entries", 0, "maximum keys held in memory before ARC eviction kicks in (0 = unbounded; memory and aof backends only)")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file; enables TLS listeners alongside the plain ones")
+	tlsKey := flag.String("tls-key", "", "TLS private key file, for -tls-cert")
+	tlsClientCA := flag.String("tls-client-ca", "", "CA certificate file to verify TLS client certificates against; requires a client cert when set")
+	noPlaintext := flag.Bool("no-plaintext", false, "disable the plain (non-TLS) listeners on :5556/:6379, for a TLS-only deployment; requires -tls-cert")
+	flag.Parse()
+
+	policy, err := parseFsyncPolicy(*aofFsync)
 	if err != nil {
 		log.Fatal(err)
 	}
-	db := &DB{
-		entries: make(map[string]string),
+	db, err := openStore(*backend, *dbPath, *aofPath, *pluginPath, policy, *maxEntries)
+	if err != nil {
+		log.Fatal(err)
 	}
 
+	tlsConfig, err := buildTLSConfig(*tlsCert, *tlsKey, *tlsClientCA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *noPlaintext && tlsConfig == nil {
+		log.Fatal("-no-plaintext requires -tls-cert")
+	}
+
+	var respLn, respTLSLn net.Listener
+
+	if !*noPlaintext {
+		log.Printf("Starting simple-kv Go server with listening port 5556, backend %s", *backend)
+		ln, err := net.Listen("tcp", ":5556")
+		if err != nil {
+			log.Fatal(err)
+		}
+		go serve(ln, db, handleConnection)
+
+		log.Printf("Starting simple-kv RESP server with listening port 6379")
+		respLn, err = net.Listen("tcp", ":6379")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if tlsConfig != nil {
+		log.Printf("Starting simple-kv Go server with TLS listening port 5557, backend %s", *backend)
+		tlsLn, err := tls.Listen("tcp", ":5557", tlsConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go serve(tlsLn, db, handleConnection)
+
+		log.Printf("Starting simple-kv RESP server with TLS listening port 6380")
+		respTLSLn, err = tls.Listen("tcp", ":6380", tlsConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	switch {
+	case respLn != nil && respTLSLn != nil:
+		go serve(respLn, db, handleRESPConnection)
+		serve(respTLSLn, db, handleRESPConnection)
+	case respLn != nil:
+		serve(respLn, db, handleRESPConnection)
+	case respTLSLn != nil:
+		serve(respTLSLn, db, handleRESPConnection)
+	}
+}
+
+// buildTLSConfig returns nil, nil when TLS is disabled (no certificate
+// configured). When a client CA is supplied, client certificates are
+// required and verified (tls.RequireAndVerifyClientCert) so operators can
+// audit which identity issued a command.
+func buildTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load TLS certificate: %s", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if clientCAFile != "" {
+		caPEM, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read TLS client CA %s: %s", clientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("unable to parse TLS client CA %s", clientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+func serve(ln net.Listener, db Store, handler func(net.Conn, Store)) {
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
 			log.Printf("unable to accept connection: %s", err)
 		} else {
-			go handleConnection(conn, db)
+			go handler(conn, db)
 		}
 	}
 }
 
-func handleConnection(conn net.Conn, db *DB) {
+// logTLSClient forces the TLS handshake and logs the connection, including
+// the verified client's CN when mutual auth is configured, so operators
+// can audit which identity issued subsequent commands. It is a no-op for
+// plain TCP connections and reports whether the caller should proceed.
+func logTLSClient(conn net.Conn) bool {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return true
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		log.Printf("tls handshake from %s failed: %s", conn.RemoteAddr(), err)
+		return false
+	}
+	peers := tlsConn.ConnectionState().PeerCertificates
+	if len(peers) > 0 {
+		log.Printf("tls connection from %s (CN=%s)", conn.RemoteAddr(), peers[0].Subject.CommonName)
+	} else {
+		log.Printf("tls connection from %s", conn.RemoteAddr())
+	}
+	return true
+}
+
+func handleConnection(conn net.Conn, db Store) {
 	defer conn.Close()
+	if !logTLSClient(conn) {
+		return
+	}
 	scanner := bufio.NewScanner(conn)
 	for scanner.Scan() {
 		words := strings.Split(scanner.Text(), " ")
@@ -64,8 +1077,10 @@ func handleConnection(conn net.Conn, db *DB) {
 				fmt.Fprintf(conn, "ERR\n")
 				continue
 			}
-			val, ok := db.Get(args[0])
-			if ok {
+			val, ok, err := db.Get(args[0])
+			if err != nil {
+				fmt.Fprintf(conn, "ERR %s\n", err)
+			} else if ok {
 				fmt.Fprintf(conn, "%s\n", val)
 			} else {
 				fmt.Fprintf(conn, "NONE\n")
@@ -75,7 +1090,10 @@ func handleConnection(conn net.Conn, db *DB) {
 				fmt.Fprintf(conn, "ERR\n")
 				continue
 			}
-			db.Put(args[0], args[1])
+			if err := db.Put(args[0], args[1]); err != nil {
+				fmt.Fprintf(conn, "ERR %s\n", err)
+				continue
+			}
 			fmt.Fprintf(conn, "OK\n")
 		default:
 			fmt.Fprintf(conn, "ERR\n")
@@ -86,3 +1104,382 @@ func handleConnection(conn net.Conn, db *DB) {
 		log.Println(err)
 	}
 }
+
+// respHandlers dispatches RESP commands, keyed by the upper-cased command
+// name, to a function that writes the reply directly to the connection.
+var respHandlers = map[string]func(conn net.Conn, db Store, args []string){
+	"GET":     respGet,
+	"SET":     respSet,
+	"DEL":     respDel,
+	"EXISTS":  respExists,
+	"MGET":    respMGet,
+	"MSET":    respMSet,
+	"PING":    respPing,
+	"COMMAND": respCommand,
+	"EXPIRE":  respExpire,
+	"TTL":     respTTL,
+	"PERSIST": respPersist,
+	"STATS":   respStats,
+}
+
+// handleRESPConnection speaks the Redis RESP wire protocol so that
+// unmodified Redis clients (redis-cli, go-redis, jedis, ...) can talk to
+// simple-kv. Clients send commands as RESP arrays of bulk strings; replies
+// are written with the matching RESP type for the command.
+func handleRESPConnection(conn net.Conn, db Store) {
+	defer conn.Close()
+	if !logTLSClient(conn) {
+		return
+	}
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			if err != errConnClosed {
+				log.Println(err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		command := strings.ToUpper(args[0])
+		if command == "QUIT" {
+			writeSimpleString(conn, "OK")
+			return
+		}
+
+		handler, ok := respHandlers[command]
+		if !ok {
+			writeError(conn, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+			continue
+		}
+		handler(conn, db, args[1:])
+	}
+}
+
+var errConnClosed = errors.New("connection closed")
+
+// readRESPCommand reads a single RESP value off of r and flattens it into
+// the command's argument words. A command is a RESP array of bulk strings;
+// inline simple strings are also accepted for convenience.
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	value, err := readRESPValue(r)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case string:
+		return strings.Fields(v), nil
+	default:
+		return nil, fmt.Errorf("unexpected RESP command type %T", value)
+	}
+}
+
+// readRESPValue reads and parses a single RESP value, dispatching on the
+// first byte of the line: '+' simple string, '-' error, ':' integer,
+// '$' bulk string, '*' array.
+func readRESPValue(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("empty RESP line")
+	}
+
+	switch line[0] {
+	case '+', '-':
+		return line[1:], nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESP integer: %s", line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESP bulk length: %s", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := readFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESP array length: %s", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		args := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			elem, err := readRESPValue(r)
+			if err != nil {
+				return nil, err
+			}
+			s, ok := elem.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected RESP bulk string array element")
+			}
+			args = append(args, s)
+		}
+		return args, nil
+	default:
+		// Inline command: treat the whole line as one string.
+		return line, nil
+	}
+}
+
+// readLine reads a \r\n-terminated line, returning it without the
+// terminator.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", errConnClosed
+	}
+	line = strings.TrimRight(line, "\r\n")
+	return line, nil
+}
+
+// readFull reads exactly len(buf) bytes, used for bulk string payloads
+// that are followed by a trailing \r\n.
+func readFull(r *bufio.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := r.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, errConnClosed
+		}
+	}
+	return n, nil
+}
+
+func writeSimpleString(conn net.Conn, s string) {
+	fmt.Fprintf(conn, "+%s\r\n", s)
+}
+
+func writeError(conn net.Conn, msg string) {
+	fmt.Fprintf(conn, "-%s\r\n", msg)
+}
+
+func writeInteger(conn net.Conn, n int) {
+	fmt.Fprintf(conn, ":%d\r\n", n)
+}
+
+func writeBulkString(conn net.Conn, s string) {
+	fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeNullBulkString(conn net.Conn) {
+	fmt.Fprintf(conn, "$-1\r\n")
+}
+
+func writeArrayHeader(conn net.Conn, n int) {
+	fmt.Fprintf(conn, "*%d\r\n", n)
+}
+
+func respGet(conn net.Conn, db Store, args []string) {
+	if len(args) != 1 {
+		writeError(conn, "ERR wrong number of arguments for 'get' command")
+		return
+	}
+	val, ok, err := db.Get(args[0])
+	if err != nil {
+		writeError(conn, fmt.Sprintf("ERR %s", err))
+		return
+	}
+	if !ok {
+		writeNullBulkString(conn)
+		return
+	}
+	writeBulkString(conn, val)
+}
+
+func respSet(conn net.Conn, db Store, args []string) {
+	switch len(args) {
+	case 2:
+		if err := db.Put(args[0], args[1]); err != nil {
+			writeError(conn, fmt.Sprintf("ERR %s", err))
+			return
+		}
+	case 4:
+		if strings.ToUpper(args[2]) != "EX" {
+			writeError(conn, "ERR syntax error")
+			return
+		}
+		seconds, err := strconv.Atoi(args[3])
+		if err != nil {
+			writeError(conn, "ERR value is not an integer or out of range")
+			return
+		}
+		if err := db.PutEX(args[0], args[1], time.Duration(seconds)*time.Second); err != nil {
+			writeError(conn, fmt.Sprintf("ERR %s", err))
+			return
+		}
+	default:
+		writeError(conn, "ERR wrong number of arguments for 'set' command")
+		return
+	}
+	writeSimpleString(conn, "OK")
+}
+
+func respExpire(conn net.Conn, db Store, args []string) {
+	if len(args) != 2 {
+		writeError(conn, "ERR wrong number of arguments for 'expire' command")
+		return
+	}
+	seconds, err := strconv.Atoi(args[1])
+	if err != nil {
+		writeError(conn, "ERR value is not an integer or out of range")
+		return
+	}
+	ok, err := db.Expire(args[0], time.Duration(seconds)*time.Second)
+	if err != nil {
+		writeError(conn, fmt.Sprintf("ERR %s", err))
+		return
+	}
+	if ok {
+		writeInteger(conn, 1)
+	} else {
+		writeInteger(conn, 0)
+	}
+}
+
+func respTTL(conn net.Conn, db Store, args []string) {
+	if len(args) != 1 {
+		writeError(conn, "ERR wrong number of arguments for 'ttl' command")
+		return
+	}
+	ttl, ok, err := db.TTL(args[0])
+	if err != nil {
+		writeError(conn, fmt.Sprintf("ERR %s", err))
+		return
+	}
+	if !ok {
+		writeInteger(conn, -2)
+		return
+	}
+	if ttl == noExpiry {
+		writeInteger(conn, -1)
+		return
+	}
+	writeInteger(conn, int(ttl/time.Second))
+}
+
+func respPersist(conn net.Conn, db Store, args []string) {
+	if len(args) != 1 {
+		writeError(conn, "ERR wrong number of arguments for 'persist' command")
+		return
+	}
+	ok, err := db.Persist(args[0])
+	if err != nil {
+		writeError(conn, fmt.Sprintf("ERR %s", err))
+		return
+	}
+	if ok {
+		writeInteger(conn, 1)
+	} else {
+		writeInteger(conn, 0)
+	}
+}
+
+func respDel(conn net.Conn, db Store, args []string) {
+	if len(args) < 1 {
+		writeError(conn, "ERR wrong number of arguments for 'del' command")
+		return
+	}
+	n := 0
+	for _, key := range args {
+		ok, err := db.Delete(key)
+		if err != nil {
+			writeError(conn, fmt.Sprintf("ERR %s", err))
+			return
+		}
+		if ok {
+			n++
+		}
+	}
+	writeInteger(conn, n)
+}
+
+func respExists(conn net.Conn, db Store, args []string) {
+	if len(args) < 1 {
+		writeError(conn, "ERR wrong number of arguments for 'exists' command")
+		return
+	}
+	n := 0
+	for _, key := range args {
+		_, ok, err := db.Get(key)
+		if err != nil {
+			writeError(conn, fmt.Sprintf("ERR %s", err))
+			return
+		}
+		if ok {
+			n++
+		}
+	}
+	writeInteger(conn, n)
+}
+
+func respMGet(conn net.Conn, db Store, args []string) {
+	if len(args) < 1 {
+		writeError(conn, "ERR wrong number of arguments for 'mget' command")
+		return
+	}
+	writeArrayHeader(conn, len(args))
+	for _, key := range args {
+		val, ok, err := db.Get(key)
+		if err != nil || !ok {
+			writeNullBulkString(conn)
+			continue
+		}
+		writeBulkString(conn, val)
+	}
+}
+
+func respMSet(conn net.Conn, db Store, args []string) {
+	if len(args) == 0 || len(args)%2 != 0 {
+		writeError(conn, "ERR wrong number of arguments for 'mset' command")
+		return
+	}
+	for i := 0; i < len(args); i += 2 {
+		if err := db.Put(args[i], args[i+1]); err != nil {
+			writeError(conn, fmt.Sprintf("ERR %s", err))
+			return
+		}
+	}
+	writeSimpleString(conn, "OK")
+}
+
+func respPing(conn net.Conn, db Store, args []string) {
+	if len(args) == 0 {
+		writeSimpleString(conn, "PONG")
+		return
+	}
+	writeBulkString(conn, args[0])
+}
+
+func respCommand(conn net.Conn, db Store, args []string) {
+	writeArrayHeader(conn, 0)
+}
+
+// respStats replies with a memcached-style stats blob: one "STAT name
+// value" line per counter, terminated with "END".
+func respStats(conn net.Conn, db Store, args []string) {
+	stats := db.Stats()
+	writeBulkString(conn, fmt.Sprintf(
+		"STAT hits %d\r\nSTAT misses %d\r\nSTAT evictions %d\r\nEND\r\n",
+		stats.Hits, stats.Misses, stats.Evictions,
+	))
+}
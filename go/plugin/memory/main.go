@@ -0,0 +1,50 @@
+// Command memory is the reference simple-kv storage plugin: it implements
+// plugin.KVStore with the same in-memory map behavior as the built-in
+// backend, served over the go-plugin gRPC boundary so it can run as a
+// separate process.
+package main
+
+import "sync"
+
+import goplugin "github.com/hashicorp/go-plugin"
+
+import "github.com/danburkert/simple-kv/plugin"
+
+type memStore struct {
+	mu      sync.RWMutex
+	entries map[string]string
+}
+
+func (s *memStore) Get(key string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	val, ok := s.entries[key]
+	return val, ok, nil
+}
+
+func (s *memStore) Put(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = value
+	return nil
+}
+
+func (s *memStore) Delete(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.entries[key]
+	delete(s.entries, key)
+	return ok, nil
+}
+
+func main() {
+	impl := &memStore{entries: make(map[string]string)}
+
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: plugin.Handshake,
+		Plugins: map[string]goplugin.Plugin{
+			"kv": &plugin.KVPlugin{Impl: impl},
+		},
+		GRPCServer: goplugin.DefaultGRPCServer,
+	})
+}
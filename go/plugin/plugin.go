@@ -0,0 +1,122 @@
+// Package plugin defines the go-plugin boundary that lets simple-kv's
+// storage engine run as an external subprocess, speaking the KV gRPC
+// service declared in proto/kv.proto. A plugin is any binary that calls
+// go-plugin's Serve with Handshake and a KVPlugin wrapping a KVStore
+// implementation; simple-kv launches it, performs the go-plugin
+// handshake, and forwards Get, Put and Delete over gRPC.
+package plugin
+
+import context "context"
+import "errors"
+
+import goplugin "github.com/hashicorp/go-plugin"
+import grpc "google.golang.org/grpc"
+
+import "github.com/danburkert/simple-kv/proto"
+
+// Handshake is shared by the host and every plugin binary; go-plugin
+// rejects a connection whose handshake doesn't match.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "SIMPLE_KV_PLUGIN",
+	MagicCookieValue: "simple-kv",
+}
+
+// PluginMap is passed to both goplugin.Client and goplugin.Serve.
+var PluginMap = map[string]goplugin.Plugin{
+	"kv": &KVPlugin{},
+}
+
+// KVStore is the minimal storage interface a plugin must implement. It
+// mirrors the built-in Store interface's Get/Put/Delete, but omits Scan,
+// the TTL methods (PutEX, Expire, TTL, Persist) and Stats: plugins are not
+// required to support full-keyspace iteration or expiration.
+type KVStore interface {
+	Get(key string) (string, bool, error)
+	Put(key, value string) error
+	Delete(key string) (bool, error)
+}
+
+// KVPlugin is the go-plugin.Plugin implementation shared by the host and
+// plugin processes; it only knows how to wire a gRPC client/server pair,
+// not how to store data.
+type KVPlugin struct {
+	goplugin.Plugin
+
+	// Impl is set on the plugin side and served over gRPC.
+	Impl KVStore
+}
+
+func (p *KVPlugin) GRPCServer(broker *goplugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterKVServer(s, &GRPCServer{Impl: p.Impl})
+	return nil
+}
+
+func (p *KVPlugin) GRPCClient(ctx context.Context, broker *goplugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &GRPCClient{client: proto.NewKVClient(c)}, nil
+}
+
+// GRPCClient adapts a proto.KVClient to the KVStore interface, used by
+// the host process to talk to a plugin subprocess.
+type GRPCClient struct {
+	client proto.KVClient
+}
+
+func (c *GRPCClient) Get(key string) (string, bool, error) {
+	resp, err := c.client.Get(context.Background(), &proto.GetRequest{Key: key})
+	if err != nil {
+		return "", false, err
+	}
+	return resp.Value, resp.Found, nil
+}
+
+func (c *GRPCClient) Put(key, value string) error {
+	_, err := c.client.Put(context.Background(), &proto.PutRequest{Key: key, Value: value})
+	return err
+}
+
+func (c *GRPCClient) Delete(key string) (bool, error) {
+	resp, err := c.client.Delete(context.Background(), &proto.DeleteRequest{Key: key})
+	if err != nil {
+		return false, err
+	}
+	return resp.Found, nil
+}
+
+// GRPCServer adapts a KVStore to the proto.KVServer interface, used by
+// the plugin subprocess to serve the host's requests.
+type GRPCServer struct {
+	Impl KVStore
+}
+
+func (s *GRPCServer) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
+	if s.Impl == nil {
+		return nil, errors.New("plugin: no KVStore implementation registered")
+	}
+	value, found, err := s.Impl.Get(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.GetResponse{Value: value, Found: found}, nil
+}
+
+func (s *GRPCServer) Put(ctx context.Context, req *proto.PutRequest) (*proto.PutResponse, error) {
+	if s.Impl == nil {
+		return nil, errors.New("plugin: no KVStore implementation registered")
+	}
+	if err := s.Impl.Put(req.Key, req.Value); err != nil {
+		return nil, err
+	}
+	return &proto.PutResponse{}, nil
+}
+
+func (s *GRPCServer) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	if s.Impl == nil {
+		return nil, errors.New("plugin: no KVStore implementation registered")
+	}
+	found, err := s.Impl.Delete(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.DeleteResponse{Found: found}, nil
+}
@@ -0,0 +1,60 @@
+package plugin_test
+
+import "os/exec"
+import "path/filepath"
+import "testing"
+
+import goplugin "github.com/hashicorp/go-plugin"
+
+import "github.com/danburkert/simple-kv/plugin"
+
+// TestPluginRoundTrip builds the reference memory plugin and exercises it
+// as a real subprocess over the go-plugin gRPC boundary, verifying that
+// Put/Get/Delete round-trip through the handshake and wire protocol
+// exactly like the in-process backends do.
+func TestPluginRoundTrip(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "memory-plugin")
+	build := exec.Command("go", "build", "-o", bin, "github.com/danburkert/simple-kv/plugin/memory")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building reference plugin: %s\n%s", err, out)
+	}
+
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig:  plugin.Handshake,
+		Plugins:          plugin.PluginMap,
+		Cmd:              exec.Command(bin),
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+	})
+	defer client.Kill()
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		t.Fatalf("connecting to plugin: %s", err)
+	}
+	raw, err := rpcClient.Dispense("kv")
+	if err != nil {
+		t.Fatalf("dispensing kv plugin: %s", err)
+	}
+	kv, ok := raw.(plugin.KVStore)
+	if !ok {
+		t.Fatalf("dispensed plugin does not implement KVStore")
+	}
+
+	if _, found, err := kv.Get("missing"); err != nil || found {
+		t.Fatalf("expected missing key to be not found, got found=%v err=%v", found, err)
+	}
+
+	if err := kv.Put("foo", "bar"); err != nil {
+		t.Fatalf("put: %s", err)
+	}
+	if val, found, err := kv.Get("foo"); err != nil || !found || val != "bar" {
+		t.Fatalf("get after put: val=%q found=%v err=%v", val, found, err)
+	}
+
+	if found, err := kv.Delete("foo"); err != nil || !found {
+		t.Fatalf("delete: found=%v err=%v", found, err)
+	}
+	if _, found, err := kv.Get("foo"); err != nil || found {
+		t.Fatalf("expected deleted key to be not found, got found=%v err=%v", found, err)
+	}
+}
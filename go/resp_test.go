@@ -0,0 +1,75 @@
+package main
+
+import "bufio"
+import "reflect"
+import "strings"
+import "testing"
+
+// TestReadRESPCommandArray covers the wire format real clients use: a RESP
+// array of bulk strings, as sent by redis-cli and every RESP client library.
+func TestReadRESPCommandArray(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"))
+
+	got, err := readRESPCommand(r)
+	if err != nil {
+		t.Fatalf("readRESPCommand: %s", err)
+	}
+	want := []string{"SET", "foo", "bar"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("readRESPCommand = %v, want %v", got, want)
+	}
+}
+
+// TestReadRESPCommandInline covers the inline command form (a bare line with
+// no leading type byte), which the RESP spec allows as a convenience for
+// hand-typed connections like telnet.
+func TestReadRESPCommandInline(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("PING\r\n"))
+
+	got, err := readRESPCommand(r)
+	if err != nil {
+		t.Fatalf("readRESPCommand: %s", err)
+	}
+	want := []string{"PING"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("readRESPCommand = %v, want %v", got, want)
+	}
+}
+
+// TestReadRESPValueNullBulkString covers the null bulk string ($-1), used
+// to represent a missing value (e.g. a failed GET) inside a RESP reply.
+func TestReadRESPValueNullBulkString(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("$-1\r\n"))
+
+	got, err := readRESPValue(r)
+	if err != nil {
+		t.Fatalf("readRESPValue: %s", err)
+	}
+	if got != nil {
+		t.Fatalf("readRESPValue = %v, want nil", got)
+	}
+}
+
+// TestReadRESPValueInteger covers the ':' integer type, used for RESP
+// replies like DEL's and EXISTS's counts.
+func TestReadRESPValueInteger(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(":42\r\n"))
+
+	got, err := readRESPValue(r)
+	if err != nil {
+		t.Fatalf("readRESPValue: %s", err)
+	}
+	if got != int64(42) {
+		t.Fatalf("readRESPValue = %v (%T), want int64(42)", got, got)
+	}
+}
+
+// TestReadRESPValueBulkStringBadLength rejects a malformed bulk length so a
+// corrupt or malicious client can't wedge the parser.
+func TestReadRESPValueBulkStringBadLength(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("$notanumber\r\n"))
+
+	if _, err := readRESPValue(r); err == nil {
+		t.Fatalf("readRESPValue: expected an error for a non-numeric bulk length")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+import "time"
+
+// TestExpireTTLPersist covers the basic per-key TTL lifecycle: EXPIRE sets
+// a deadline, TTL reports it, and PERSIST clears it back to noExpiry.
+func TestExpireTTLPersist(t *testing.T) {
+	db := NewMemStore(0)
+	mustPut(t, db, "a", "1")
+
+	ok, err := db.Expire("a", time.Hour)
+	if err != nil || !ok {
+		t.Fatalf("Expire: ok=%v err=%v", ok, err)
+	}
+
+	ttl, found, err := db.TTL("a")
+	if err != nil || !found || ttl <= 0 || ttl > time.Hour {
+		t.Fatalf("TTL after Expire: ttl=%v found=%v err=%v", ttl, found, err)
+	}
+
+	persisted, err := db.Persist("a")
+	if err != nil || !persisted {
+		t.Fatalf("Persist: persisted=%v err=%v", persisted, err)
+	}
+
+	ttl, found, err = db.TTL("a")
+	if err != nil || !found || ttl != noExpiry {
+		t.Fatalf("TTL after Persist: ttl=%v found=%v err=%v", ttl, found, err)
+	}
+}
+
+// TestSweepOnceReclaimsExpiredKeys exercises the active sweeper directly:
+// a key past its deadline should be gone from entries (and its TTL index)
+// after a single sweepOnce, while a persistent key living alongside it is
+// left untouched.
+func TestSweepOnceReclaimsExpiredKeys(t *testing.T) {
+	db := NewMemStore(0)
+	mustPut(t, db, "persistent", "1")
+	if err := db.PutEX("expiring", "2", time.Millisecond); err != nil {
+		t.Fatalf("PutEX: %s", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	db.sweepOnce()
+
+	if _, found, _ := db.Get("expiring"); found {
+		t.Fatalf("expiring: expected reclaimed by sweepOnce, still found")
+	}
+	mustHit(t, db, "persistent", "1")
+
+	db.mu.Lock()
+	_, stillIndexed := db.withTTL["expiring"]
+	db.mu.Unlock()
+	if stillIndexed {
+		t.Fatalf("expiring: still present in the TTL index after being swept")
+	}
+}
+
+// TestSweepOnceSkipsPersistentKeys checks that sweepOnce's sample is drawn
+// from TTL-bearing keys only: with nothing but persistent keys in the
+// store, a sweep finds no sample and reports zero expired.
+func TestSweepOnceSkipsPersistentKeys(t *testing.T) {
+	db := NewMemStore(0)
+	for _, key := range []string{"a", "b", "c"} {
+		mustPut(t, db, key, "v")
+	}
+
+	if ratio := db.sweepOnce(); ratio != 0 {
+		t.Fatalf("sweepOnce ratio = %v, want 0 with no TTL-bearing keys", ratio)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		mustHit(t, db, key, "v")
+	}
+}